@@ -0,0 +1,151 @@
+// Copyright 2013, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package raspicam
+
+import (
+	"os/exec"
+	"sync"
+)
+
+// Backend selects which camera stack a CaptureCommand targets.
+//
+// Raspberry Pi OS up to Bullseye ships the legacy raspistill/raspivid/raspiyuv
+// tools built on the closed firmware camera stack. Bullseye onwards (and Pi 5,
+// which has no legacy stack at all) uses libcamera-based tools instead:
+// libcamera-still/libcamera-vid/libcamera-raw, renamed to
+// rpicam-still/rpicam-vid/rpicam-raw from Bookworm onwards. Backend lets a
+// CaptureCommand target either stack, or detect automatically.
+type Backend uint
+
+const (
+	// BackendAuto detects the available backend the first time it is
+	// resolved (by a CaptureCommand's Cmd, Params or String method), by
+	// looking for the libcamera
+	// and rpicam binaries on PATH and falling back to the legacy tools if
+	// none are found. It is the zero value, so a CaptureCommand with no
+	// Backend set auto-detects.
+	BackendAuto Backend = iota
+
+	// BackendLegacy targets raspistill/raspivid/raspiyuv.
+	BackendLegacy
+
+	// BackendLibcamera targets the libcamera/rpicam-based tools.
+	BackendLibcamera
+)
+
+var backendNames = [...]string{
+	"auto",
+	"legacy",
+	"libcamera",
+}
+
+// String returns the name of the Backend.
+func (b Backend) String() string { return backendNames[b] }
+
+// commandKind identifies which of the three raspicam tools a CaptureCommand
+// corresponds to, so backendCommand can look up the right binary name for
+// each Backend.
+type commandKind uint
+
+const (
+	kindStill commandKind = iota
+	kindStillYUV
+	kindVid
+)
+
+// legacyCommand returns the legacy binary used to implement kind, read from
+// the package's overridable Default* command variables at call time.
+func legacyCommand(kind commandKind) string {
+	switch kind {
+	case kindStill:
+		return DefaultRaspiStillCommand
+	case kindStillYUV:
+		return DefaultRaspiStillYUVCommand
+	}
+	return DefaultRaspiVidCommmand
+}
+
+// libcameraCommands maps a commandKind to the libcamera-apps binary that
+// replaces it. libcamera-raw is the closest equivalent of raspiyuv: it dumps
+// unprocessed sensor frames rather than encoding them.
+var libcameraCommands = [...]string{
+	kindStill:    "libcamera-still",
+	kindStillYUV: "libcamera-raw",
+	kindVid:      "libcamera-vid",
+}
+
+// rpicamCommands are the names libcamera-apps was renamed to from Bookworm
+// onwards; they're tried before the older libcamera-* names when detecting
+// or resolving BackendLibcamera.
+var rpicamCommands = [...]string{
+	kindStill:    "rpicam-still",
+	kindStillYUV: "rpicam-raw",
+	kindVid:      "rpicam-vid",
+}
+
+// lookPath is exec.LookPath by default; overridden in tests.
+var lookPath = exec.LookPath
+
+var (
+	detectOnce      sync.Once
+	detectedBackend Backend
+)
+
+// detectBackend probes PATH once for an rpicam-* or libcamera-* binary and
+// caches the result for the life of the process, preferring
+// BackendLibcamera whenever any of those binaries are present.
+func detectBackend() Backend {
+	detectOnce.Do(func() {
+		detectedBackend = BackendLegacy
+		for _, name := range rpicamCommands {
+			if _, err := lookPath(name); err == nil {
+				detectedBackend = BackendLibcamera
+				return
+			}
+		}
+		for _, name := range libcameraCommands {
+			if _, err := lookPath(name); err == nil {
+				detectedBackend = BackendLibcamera
+				return
+			}
+		}
+	})
+	return detectedBackend
+}
+
+// resolveBackend expands BackendAuto to the backend detectBackend finds on
+// PATH; BackendLegacy and BackendLibcamera pass through unchanged. Unlike
+// backendCommand, this never consults an explicit command override: it
+// answers "which tool family will actually run", which callers need to
+// decide whether to emit flags (e.g. TuningFile, Vid.Codec) that only the
+// libcamera/rpicam tools understand.
+func resolveBackend(b Backend) Backend {
+	if b == BackendAuto {
+		return detectBackend()
+	}
+	return b
+}
+
+// backendCommand resolves the binary to invoke for a CaptureCommand given an
+// explicit command override, a Backend selection and the kind of command
+// being built. An explicit override always wins. Otherwise BackendAuto
+// resolves via detectBackend, and BackendLibcamera prefers the newer
+// rpicam-* name over the libcamera-* one when both are possible, falling
+// back to libcamera-* if rpicam-* isn't found on PATH.
+func backendCommand(explicit string, b Backend, kind commandKind) string {
+	if explicit != "" {
+		return explicit
+	}
+	if b == BackendAuto {
+		b = detectBackend()
+	}
+	if b == BackendLibcamera {
+		if _, err := lookPath(rpicamCommands[kind]); err == nil {
+			return rpicamCommands[kind]
+		}
+		return libcameraCommands[kind]
+	}
+	return legacyCommand(kind)
+}
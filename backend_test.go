@@ -0,0 +1,93 @@
+// Copyright 2013, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package raspicam
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// withLookPath stubs lookPath for the duration of a test, restoring it on
+// cleanup, and resets the detectBackend cache so BackendAuto is re-probed.
+func withLookPath(t *testing.T, found map[string]bool) {
+	t.Helper()
+	old := lookPath
+	lookPath = func(name string) (string, error) {
+		if found[name] {
+			return name, nil
+		}
+		return "", errors.New("not found")
+	}
+	detectOnce = sync.Once{}
+	t.Cleanup(func() {
+		lookPath = old
+		detectOnce = sync.Once{}
+	})
+}
+
+func TestBackendCommandExplicit(t *testing.T) {
+	withLookPath(t, nil)
+
+	if got := backendCommand("mycommand", BackendLegacy, kindVid); got != "mycommand" {
+		t.Errorf("backendCommand() = %v, want mycommand", got)
+	}
+}
+
+func TestBackendCommandLegacy(t *testing.T) {
+	withLookPath(t, nil)
+
+	if got := backendCommand("", BackendLegacy, kindStill); got != DefaultRaspiStillCommand {
+		t.Errorf("backendCommand() = %v, want %v", got, DefaultRaspiStillCommand)
+	}
+}
+
+func TestBackendCommandLibcameraPrefersRpicam(t *testing.T) {
+	withLookPath(t, map[string]bool{"rpicam-vid": true, "libcamera-vid": true})
+
+	if got := backendCommand("", BackendLibcamera, kindVid); got != "rpicam-vid" {
+		t.Errorf("backendCommand() = %v, want rpicam-vid", got)
+	}
+}
+
+func TestBackendCommandLibcameraFallsBackToLibcameraName(t *testing.T) {
+	withLookPath(t, map[string]bool{"libcamera-still": true})
+
+	if got := backendCommand("", BackendLibcamera, kindStill); got != "libcamera-still" {
+		t.Errorf("backendCommand() = %v, want libcamera-still", got)
+	}
+}
+
+func TestBackendCommandAutoDetectsLibcamera(t *testing.T) {
+	withLookPath(t, map[string]bool{"rpicam-still": true})
+
+	if got := backendCommand("", BackendAuto, kindStill); got != "rpicam-still" {
+		t.Errorf("backendCommand() = %v, want rpicam-still", got)
+	}
+}
+
+func TestBackendCommandAutoFallsBackToLegacy(t *testing.T) {
+	withLookPath(t, nil)
+
+	if got := backendCommand("", BackendAuto, kindVid); got != DefaultRaspiVidCommmand {
+		t.Errorf("backendCommand() = %v, want %v", got, DefaultRaspiVidCommmand)
+	}
+}
+
+func TestBackendString(t *testing.T) {
+	tests := []struct {
+		b    Backend
+		want string
+	}{
+		{BackendAuto, "auto"},
+		{BackendLegacy, "legacy"},
+		{BackendLibcamera, "libcamera"},
+	}
+	for _, test := range tests {
+		if got := test.b.String(); got != test.want {
+			t.Errorf("%v.String() = %v, want %v", int(test.b), got, test.want)
+		}
+	}
+}
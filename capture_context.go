@@ -0,0 +1,154 @@
+// Copyright 2013, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package raspicam
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ShutdownGracePeriod is how long CaptureContext waits after sending
+// SIGINT to a cancelled capture before escalating to SIGKILL.
+var ShutdownGracePeriod = 2 * time.Second
+
+// CaptureErrorKind identifies which phase of a capture a *CaptureError
+// happened in.
+type CaptureErrorKind uint
+
+const (
+	// ErrStart indicates the capture process couldn't be started.
+	ErrStart CaptureErrorKind = iota
+	// ErrRuntime indicates a failure while the capture was running: an
+	// I/O error copying its output, or (when no stderrHandler is given to
+	// CaptureContext) non-empty output on stderr.
+	ErrRuntime
+	// ErrExit indicates the capture process exited abnormally.
+	ErrExit
+)
+
+var captureErrorKinds = [...]string{
+	"start",
+	"runtime",
+	"exit",
+}
+
+// String returns the name of the CaptureErrorKind.
+func (k CaptureErrorKind) String() string { return captureErrorKinds[k] }
+
+// CaptureError is returned by CaptureContext when a capture fails,
+// identifying which phase of the capture failed.
+type CaptureError struct {
+	Kind CaptureErrorKind
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *CaptureError) Error() string {
+	return fmt.Sprintf("raspicam: %v: %v", e.Kind, e.Err)
+}
+
+// Unwrap returns the underlying error, for errors.Is/errors.As.
+func (e *CaptureError) Unwrap() error { return e.Err }
+
+// CaptureContext runs c, writing its output to w, until the capture
+// finishes or ctx is cancelled. Unlike Capture, it never leaves the child
+// process running if the caller stops reading w or abandons the call: on
+// cancellation it sends SIGINT, giving the child a chance to flush and
+// close down cleanly (important for muxed formats like H.264), then
+// SIGKILL if it hasn't exited within ShutdownGracePeriod.
+//
+// If stderrHandler is non-nil, each line the child writes to stderr is
+// passed to it as it arrives. Otherwise stderr lines are aggregated and,
+// if the capture fails, returned as part of the error rather than mingled
+// with I/O errors on a shared channel as Capture does.
+func CaptureContext(ctx context.Context, c CaptureCommand, w io.Writer, stderrHandler func(line string)) error {
+	cmd := exec.Command(c.Cmd(), c.Params()...)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return &CaptureError{Kind: ErrStart, Err: err}
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return &CaptureError{Kind: ErrStart, Err: err}
+	}
+	if err := cmd.Start(); err != nil {
+		return &CaptureError{Kind: ErrStart, Err: err}
+	}
+
+	var mu sync.Mutex
+	var stderrLines []string
+	stderrDone := make(chan struct{})
+	go func() {
+		defer close(stderrDone)
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if stderrHandler != nil {
+				stderrHandler(line)
+				continue
+			}
+			mu.Lock()
+			stderrLines = append(stderrLines, line)
+			mu.Unlock()
+		}
+	}()
+
+	copyDone := make(chan error, 1)
+	go func() {
+		_, cerr := io.Copy(w, stdout)
+		copyDone <- cerr
+	}()
+
+	stopWatch := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cmd.Process.Signal(syscall.SIGINT)
+			select {
+			case <-time.After(ShutdownGracePeriod):
+				cmd.Process.Kill()
+			case <-stopWatch:
+			}
+		case <-stopWatch:
+		}
+	}()
+
+	// cmd.Wait must not run until both the stdout copy and the stderr scan
+	// have finished reading their pipes: Wait closes them as soon as the
+	// process exits, and reading from a pipe concurrently with Wait is a
+	// race (see the os/exec docs for StdoutPipe/StderrPipe).
+	copyErr := <-copyDone
+	<-stderrDone
+	close(stopWatch)
+	waitErr := cmd.Wait()
+
+	if copyErr != nil && copyErr != io.EOF {
+		return &CaptureError{Kind: ErrRuntime, Err: copyErr}
+	}
+	if waitErr != nil {
+		if stderrHandler == nil {
+			mu.Lock()
+			lines := append([]string(nil), stderrLines...)
+			mu.Unlock()
+			if len(lines) > 0 {
+				waitErr = fmt.Errorf("%v: %v", waitErr, strings.Join(lines, "; "))
+			}
+		}
+		return &CaptureError{Kind: ErrExit, Err: waitErr}
+	}
+	if stderrHandler == nil && len(stderrLines) > 0 {
+		return &CaptureError{Kind: ErrRuntime, Err: errors.New(strings.Join(stderrLines, "; "))}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2013, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package raspicam
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCaptureContextSuccess(t *testing.T) {
+	var out bytes.Buffer
+	err := CaptureContext(context.Background(), fakeCommand{cmd: "printf", params: []string{"hello"}}, &out, nil)
+	if err != nil {
+		t.Fatalf("CaptureContext() = %v", err)
+	}
+	if out.String() != "hello" {
+		t.Errorf("CaptureContext() wrote %q, want %q", out.String(), "hello")
+	}
+}
+
+func TestCaptureContextExitError(t *testing.T) {
+	var out bytes.Buffer
+	err := CaptureContext(context.Background(), fakeCommand{cmd: "sh", params: []string{"-c", "exit 1"}}, &out, nil)
+	if err == nil {
+		t.Fatal("CaptureContext() = nil, want an error")
+	}
+	cerr, ok := err.(*CaptureError)
+	if !ok {
+		t.Fatalf("CaptureContext() returned %T, want *CaptureError", err)
+	}
+	if cerr.Kind != ErrExit {
+		t.Errorf("CaptureError.Kind = %v, want %v", cerr.Kind, ErrExit)
+	}
+}
+
+func TestCaptureContextStderrHandler(t *testing.T) {
+	var lines []string
+	err := CaptureContext(context.Background(), fakeCommand{cmd: "sh", params: []string{"-c", "echo warning 1>&2"}}, &bytes.Buffer{}, func(line string) {
+		lines = append(lines, line)
+	})
+	if err != nil {
+		t.Fatalf("CaptureContext() = %v", err)
+	}
+	if len(lines) != 1 || lines[0] != "warning" {
+		t.Errorf("StderrHandler saw %v, want [warning]", lines)
+	}
+}
+
+func TestCaptureContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- CaptureContext(ctx, fakeCommand{cmd: "sleep", params: []string{"30"}}, &bytes.Buffer{}, nil)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("CaptureContext() did not return after cancellation")
+	}
+}
@@ -176,6 +176,40 @@ func (c ColourFX) String() string {
 	return fmt.Sprintf("%v:%v", c.U, c.V)
 }
 
+// AWBGains specifies explicit red/blue white balance gains to apply instead
+// of an automatic AWBMode.  Setting either to a non-zero value makes
+// Camera.params emit "--awb off" alongside "--awbgains", overriding
+// whatever AWBMode is set to, since fixed gains and auto white balance
+// can't be active at the same time.
+type AWBGains struct {
+	Red, Blue float64
+}
+
+// String returns the command parameter for the given AWBGains.
+func (a AWBGains) String() string {
+	return fmt.Sprintf("%v,%v", a.Red, a.Blue)
+}
+
+// DenoiseMode is an enumeration of the supported denoise algorithms.
+type DenoiseMode uint
+
+const (
+	DenoiseOff DenoiseMode = iota
+	DenoiseCDNOff
+	DenoiseCDNFast
+	DenoiseCDNHQ
+)
+
+var denoiseModes = [...]string{
+	"off",
+	"cdn_off",
+	"cdn_fast",
+	"cdn_hq",
+}
+
+// String returns the command-line parameter for the given DenoiseMode.
+func (d DenoiseMode) String() string { return denoiseModes[d] }
+
 // FloatRect contains the information necessary to construct a rectangle
 // with dimensions in floating point.
 type FloatRect struct {
@@ -202,12 +236,25 @@ type Camera struct {
 	ExposureMode         ExposureMode
 	MeteringMode         MeteringMode
 	AWBMode              AWBMode
+	AWBGains             AWBGains
+	AnalogueGain         float64 // Analogue (sensor) gain
+	DigitalGain          float64 // Digital (ISP) gain
 	ImageEffect          ImageFX
 	ColourEffects        ColourFX
 	Rotation             int // 0 to 359
 	HFlip, VFlip         bool
 	RegionOfInterest     FloatRect // Assumes Normalised to [0.0,1.0]
 	ShutterSpeed         time.Duration
+	Denoise              DenoiseMode
+	TuningFile           string        // Path to a libcamera tuning file
+	FlickerPeriod        time.Duration // Period of mains flicker to cancel, e.g. 10ms for 50Hz
+
+	// SensorMode selects a fixed sensor mode instead of letting the camera
+	// auto-select one based on resolution and framerate.  0 means auto;
+	// see sensorModeCapabilities for the documented modes this package
+	// knows how to validate against (Camera Module v2 modes 1-7; HQ and
+	// v3 modules support additional modes not covered here).
+	SensorMode int
 }
 
 // The default Camera setup.
@@ -223,9 +270,11 @@ var defaultCamera = Camera{
 }
 
 // String returns the parameters necessary to construct the
-// equivalent command line arguments for the raspicam tools.
+// equivalent command line arguments for the raspicam tools, resolving
+// BackendAuto the same way Cmd does to decide whether to include
+// libcamera-only flags.
 func (c *Camera) String() string {
-	return paramString(c)
+	return strings.Join(c.params(resolveBackend(BackendAuto)), " ")
 }
 
 // params is a wrapper around a string slice which adds convenience
@@ -235,12 +284,18 @@ type params []string
 func (ps *params) add(xs ...string)           { *ps = append(*ps, xs...) }
 func (ps *params) addInt(x string, n int)     { *ps = append(*ps, x, strconv.Itoa(n)) }
 func (ps *params) addInt64(x string, n int64) { *ps = append(*ps, x, strconv.FormatInt(n, 10)) }
+func (ps *params) addFloat64(x string, f float64) {
+	*ps = append(*ps, x, strconv.FormatFloat(f, 'g', -1, 64))
+}
 
 func paramString(x interface{ params() []string }) string {
 	return strings.Join(x.params(), " ")
 }
 
-func (c *Camera) params() []string {
+// params builds the command-line parameters for c. b is the resolved
+// Backend the caller will actually invoke, used to gate flags (TuningFile)
+// that only the libcamera/rpicam tools accept.
+func (c *Camera) params(b Backend) []string {
 	var out params
 	if c.Sharpness != defaultCamera.Sharpness {
 		out.addInt("--sharpness", c.Sharpness)
@@ -269,9 +324,20 @@ func (c *Camera) params() []string {
 	if c.MeteringMode != defaultCamera.MeteringMode {
 		out.add("--metering", c.MeteringMode.String())
 	}
-	if c.AWBMode != defaultCamera.AWBMode {
+	if c.AWBGains != defaultCamera.AWBGains {
+		// Fixed gains and auto white balance can't be active at once, so
+		// AWBGains always forces AWBOff regardless of AWBMode.
+		out.add("--awb", AWBOff.String())
+		out.add("--awbgains", c.AWBGains.String())
+	} else if c.AWBMode != defaultCamera.AWBMode {
 		out.add("--awb", c.AWBMode.String())
 	}
+	if c.AnalogueGain != defaultCamera.AnalogueGain {
+		out.addFloat64("--analoggain", c.AnalogueGain)
+	}
+	if c.DigitalGain != defaultCamera.DigitalGain {
+		out.addFloat64("--digitalgain", c.DigitalGain)
+	}
 	if c.ImageEffect != defaultCamera.ImageEffect {
 		out.add("--imxfx", c.ImageEffect.String())
 	}
@@ -296,6 +362,18 @@ func (c *Camera) params() []string {
 	if c.ShutterSpeed != defaultCamera.ShutterSpeed {
 		out.addInt64("--shutter", int64(c.ShutterSpeed/time.Microsecond))
 	}
+	if c.Denoise != defaultCamera.Denoise {
+		out.add("--denoise", c.Denoise.String())
+	}
+	if c.TuningFile != defaultCamera.TuningFile && b == BackendLibcamera {
+		out.add("--tuning-file", c.TuningFile)
+	}
+	if c.FlickerPeriod != defaultCamera.FlickerPeriod {
+		out.addInt64("--flicker", int64(c.FlickerPeriod/time.Microsecond))
+	}
+	if c.SensorMode != defaultCamera.SensorMode {
+		out.addInt("--mode", c.SensorMode)
+	}
 	return out
 }
 
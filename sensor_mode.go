@@ -0,0 +1,57 @@
+// Copyright 2013, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package raspicam
+
+import "fmt"
+
+// sensorModeCapability describes the maximum resolution and framerate
+// range a SensorMode supports.
+type sensorModeCapability struct {
+	Width, Height              int
+	MinFramerate, MaxFramerate float64
+}
+
+// sensorModeCapabilities documents the Camera Module v2's sensor modes, as
+// set out in the Raspberry Pi camera documentation. It's deliberately
+// small: HQ and v3 modules support additional or different modes, which
+// aren't validated here. Mode 0 (auto) is never present, so it's never
+// validated against.
+var sensorModeCapabilities = map[int]sensorModeCapability{
+	1: {1920, 1080, 0.1, 30},
+	2: {3280, 2464, 0.1, 15},
+	3: {3280, 2464, 0.1, 15},
+	4: {1640, 1232, 0.1, 40},
+	5: {1640, 922, 0.1, 40},
+	6: {1280, 720, 40, 90},
+	7: {640, 480, 40, 90},
+}
+
+// validateSensorResolution checks width/height against mode's documented
+// capability, if any. Mode 0 (auto) and unrecognised modes aren't
+// checked.
+func validateSensorResolution(mode, width, height int) error {
+	cap, ok := sensorModeCapabilities[mode]
+	if !ok {
+		return nil
+	}
+	if width > cap.Width || height > cap.Height {
+		return fmt.Errorf("raspicam: sensor mode %v supports at most %vx%v, got %vx%v", mode, cap.Width, cap.Height, width, height)
+	}
+	return nil
+}
+
+// validateSensorFramerate checks a framerate range against mode's
+// documented capability, if any. Mode 0 (auto) and unrecognised modes
+// aren't checked.
+func validateSensorFramerate(mode int, min, max float64) error {
+	cap, ok := sensorModeCapabilities[mode]
+	if !ok {
+		return nil
+	}
+	if min < cap.MinFramerate || max > cap.MaxFramerate {
+		return fmt.Errorf("raspicam: sensor mode %v supports %v-%vfps, got %v-%vfps", mode, cap.MinFramerate, cap.MaxFramerate, min, max)
+	}
+	return nil
+}
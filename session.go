@@ -0,0 +1,190 @@
+// Copyright 2013, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package raspicam
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// Frame is a chunk of raw bytes read from a Session's underlying capture
+// process as it runs.
+type Frame []byte
+
+// Session represents a long-running capture that stays alive so it can be
+// reconfigured without restarting the underlying process: by writing to
+// its stdin control channel, in the style of raspivid/libcamera-vid's
+// keypress ("-k"/"--keypress") mode, or by sending it SIGUSR1/SIGUSR2 to
+// trigger a segment split or a still capture mid-video, as the legacy and
+// libcamera tools both support.
+type Session struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	frames chan Frame
+	errCh  chan error
+
+	// closing is closed by Close to unblock readFrames/readErrors if the
+	// caller has stopped draining Frames()/Errors(): without it, a send on
+	// frames/errCh with no one receiving would block those goroutines
+	// forever, and Close would then hang waiting for them.
+	closing chan struct{}
+
+	readDone  sync.WaitGroup
+	closeOnce sync.Once
+	waitDone  chan struct{}
+	waitErr   error
+}
+
+// Start runs c with its keypress control channel enabled, returning a
+// *Session that stays alive until ctx is cancelled or Session.Close is
+// called.
+func Start(ctx context.Context, c CaptureCommand) (*Session, error) {
+	args := append(append([]string{}, c.Params()...), "--keypress")
+	cmd := exec.CommandContext(ctx, c.Cmd(), args...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Session{
+		cmd:      cmd,
+		stdin:    stdin,
+		frames:   make(chan Frame),
+		errCh:    make(chan error, 8),
+		closing:  make(chan struct{}),
+		waitDone: make(chan struct{}),
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	s.readDone.Add(2)
+	go func() {
+		defer s.readDone.Done()
+		s.readFrames(stdout)
+	}()
+	go func() {
+		defer s.readDone.Done()
+		s.readErrors(stderr)
+	}()
+	go func() {
+		// cmd.Wait must not run until readFrames/readErrors have finished
+		// reading stdout/stderr: Wait closes those pipes as soon as the
+		// process exits, and reading from a pipe concurrently with Wait is
+		// a race (see the os/exec docs for StdoutPipe/StderrPipe).
+		s.readDone.Wait()
+		s.waitErr = cmd.Wait()
+		close(s.waitDone)
+	}()
+
+	return s, nil
+}
+
+// Frames returns the channel frames read from the capture's output are
+// delivered on. It is closed once the capture's output is exhausted.
+func (s *Session) Frames() <-chan Frame { return s.frames }
+
+// Errors returns the channel non-fatal errors encountered while the
+// session runs (currently, stderr lines from the capture process) are
+// delivered on. It is closed once the capture exits.
+func (s *Session) Errors() <-chan error { return s.errCh }
+
+// Close signals the capture to stop and waits for it to exit, returning
+// any error from the process. It is safe to call more than once.
+func (s *Session) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closing)
+		s.stdin.Close()
+		if s.cmd.Process != nil {
+			s.cmd.Process.Signal(syscall.SIGINT)
+		}
+		<-s.waitDone
+	})
+	return s.waitErr
+}
+
+func (s *Session) readFrames(r io.Reader) {
+	defer close(s.frames)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			frame := make(Frame, n)
+			copy(frame, buf[:n])
+			select {
+			case s.frames <- frame:
+			case <-s.closing:
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Session) readErrors(r io.Reader) {
+	defer close(s.errCh)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		select {
+		case s.errCh <- fmt.Errorf("%v: %v", s.cmd.Path, scanner.Text()):
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+// signal sends sig to the underlying process.
+func (s *Session) signal(sig syscall.Signal) error {
+	if s.cmd.Process == nil {
+		return errors.New("raspicam: session has no running process")
+	}
+	return s.cmd.Process.Signal(sig)
+}
+
+// SendControl writes "key value" to the capture's stdin control channel,
+// the convention used by this package's Session to pass runtime parameter
+// changes through to raspivid/libcamera-vid's keypress mode.
+func (s *Session) SendControl(key, val string) error {
+	_, err := fmt.Fprintf(s.stdin, "%v %v\n", key, val)
+	return err
+}
+
+// UpdateBrightness adjusts the running capture's brightness without
+// restarting it.
+func (s *Session) UpdateBrightness(n int) error {
+	return s.SendControl("br", strconv.Itoa(n))
+}
+
+// SplitSegment signals the running video capture to start a new output
+// segment (SIGUSR1).
+func (s *Session) SplitSegment() error {
+	return s.signal(syscall.SIGUSR1)
+}
+
+// TriggerCapture signals the running video capture to take a still capture
+// without interrupting the video (SIGUSR2).
+func (s *Session) TriggerCapture() error {
+	return s.signal(syscall.SIGUSR2)
+}
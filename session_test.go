@@ -0,0 +1,73 @@
+// Copyright 2013, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package raspicam
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeCommand is a minimal CaptureCommand used to exercise Session against
+// an ordinary shell command instead of raspivid/libcamera-vid.
+type fakeCommand struct {
+	cmd    string
+	params []string
+}
+
+func (f fakeCommand) Cmd() string      { return f.cmd }
+func (f fakeCommand) Params() []string { return f.params }
+
+func TestSessionFrames(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s, err := Start(ctx, fakeCommand{cmd: "printf", params: []string{"hello"}})
+	if err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+
+	var got bytes.Buffer
+	for frame := range s.Frames() {
+		got.Write(frame)
+	}
+	for range s.Errors() {
+		// drain
+	}
+
+	if got.String() != "hello" {
+		t.Errorf("Frames() produced %q, want %q", got.String(), "hello")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+}
+
+// TestSessionCloseWithoutDraining checks that Close returns promptly even
+// if the caller has stopped reading Frames(), which would otherwise block
+// readFrames forever on an unreceived send.
+func TestSessionCloseWithoutDraining(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s, err := Start(ctx, fakeCommand{cmd: "yes", params: nil})
+	if err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+
+	<-s.Frames()
+	<-s.Frames()
+
+	done := make(chan error, 1)
+	go func() { done <- s.Close() }()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close() did not return after the caller stopped draining Frames()")
+	}
+}
@@ -51,6 +51,10 @@ type BaseStill struct {
 	// command is used.
 	Command string
 
+	// Backend selects which camera stack Cmd resolves Command against when
+	// Command is blank.  Defaults to BackendAuto.
+	Backend Backend
+
 	// Additional arguments.  Default is empty.
 	Args []string
 }
@@ -69,6 +73,13 @@ func (s *BaseStill) String() string {
 	return paramString(s)
 }
 
+// Validate checks Width/Height against the documented capabilities of
+// Camera.SensorMode, if SensorMode is set to anything this package knows
+// about, returning an error describing the mismatch if not.
+func (s *BaseStill) Validate() error {
+	return validateSensorResolution(s.Camera.SensorMode, s.Width, s.Height)
+}
+
 func (s *BaseStill) params() []string {
 	var out params
 	out.add("--output", "-")
@@ -84,7 +95,7 @@ func (s *BaseStill) params() []string {
 	if s.Height != defaultStill.Height {
 		out.addInt("--height", s.Height)
 	}
-	out.add(s.Camera.params()...)
+	out.add(s.Camera.params(resolveBackend(s.Backend))...)
 	out.add(s.Preview.params()...)
 	return out
 }
@@ -125,10 +136,7 @@ func (s *Still) params() []string {
 
 // Cmd returns the raspicam command for a Still.
 func (s *Still) Cmd() string {
-	if s.BaseStill.Command != "" {
-		return s.BaseStill.Command
-	}
-	return DefaultRaspiStillCommand
+	return backendCommand(s.BaseStill.Command, s.BaseStill.Backend, kindStill)
 }
 
 // Params returns the parameters to be used in the command execution.
@@ -169,10 +177,7 @@ func (s *StillYUV) params() []string {
 
 // Cmd returns the raspicam command for a StillYUV.
 func (s *StillYUV) Cmd() string {
-	if s.BaseStill.Command != "" {
-		return s.BaseStill.Command
-	}
-	return DefaultRaspiStillYUVCommand
+	return backendCommand(s.BaseStill.Command, s.BaseStill.Backend, kindStillYUV)
 }
 
 // Params returns the parameters to be used in the command execution.
@@ -86,3 +86,114 @@ func TestCameraParams(t *testing.T) {
 		}
 	}
 }
+
+func TestCameraExtendedParams(t *testing.T) {
+	const paramsOut = "--output - --awb off --awbgains 1.5,1.2 --analoggain 2.5 --digitalgain 1.1 --denoise cdn_fast --tuning-file /path/to/tuning.json --flicker 10000"
+
+	testNames := [...]string{"Still", "StillYUV", "Vid"}
+
+	still := NewStill()
+	still.Backend = BackendLibcamera
+	still.Camera.AWBGains = AWBGains{Red: 1.5, Blue: 1.2}
+	still.Camera.AnalogueGain = 2.5
+	still.Camera.DigitalGain = 1.1
+	still.Camera.Denoise = DenoiseCDNFast
+	still.Camera.TuningFile = "/path/to/tuning.json"
+	still.Camera.FlickerPeriod = 10 * time.Millisecond
+
+	stillYUV := NewStillYUV()
+	stillYUV.Backend = BackendLibcamera
+	stillYUV.Camera = still.Camera
+
+	vid := NewVid()
+	vid.Backend = BackendLibcamera
+	vid.Camera = still.Camera
+
+	testCases := [...]CaptureCommand{still, stillYUV, vid}
+
+	for i, test := range testNames {
+		paramString := strings.Join(testCases[i].Params(), " ")
+		if paramString != paramsOut {
+			t.Errorf("%v: param() returned %v, expected %v", test, paramString, paramsOut)
+		}
+	}
+}
+
+// TestCameraAWBGainsOverridesAWBMode checks that setting AWBGains forces
+// "--awb off" even when AWBMode is explicitly set to something else: fixed
+// gains and auto white balance can't both be active.
+func TestCameraAWBGainsOverridesAWBMode(t *testing.T) {
+	const paramsOut = "--output - --awb off --awbgains 1.5,1.2"
+
+	still := NewStill()
+	still.Camera.AWBMode = AWBFluorescent
+	still.Camera.AWBGains = AWBGains{Red: 1.5, Blue: 1.2}
+
+	if got := strings.Join(still.Params(), " "); got != paramsOut {
+		t.Errorf("Still: param() returned %v, expected %v", got, paramsOut)
+	}
+}
+
+// TestCameraExtendedParamsLegacyOmitsTuningFile checks that TuningFile,
+// a libcamera-only concept, is not emitted against the legacy
+// raspistill/raspivid/raspiyuv tools.
+func TestCameraExtendedParamsLegacyOmitsTuningFile(t *testing.T) {
+	const paramsOut = "--output - --denoise cdn_fast --flicker 10000"
+
+	still := NewStill()
+	still.Backend = BackendLegacy
+	still.Camera.Denoise = DenoiseCDNFast
+	still.Camera.TuningFile = "/path/to/tuning.json"
+	still.Camera.FlickerPeriod = 10 * time.Millisecond
+
+	if got := strings.Join(still.Params(), " "); got != paramsOut {
+		t.Errorf("Still: param() returned %v, expected %v", got, paramsOut)
+	}
+}
+
+func TestSensorModeParam(t *testing.T) {
+	const paramsOut = "--output - --mode 7"
+
+	still := NewStill()
+	still.Camera.SensorMode = 7
+
+	vid := NewVid()
+	vid.Camera.SensorMode = 7
+
+	testCases := map[string]CaptureCommand{"Still": still, "Vid": vid}
+	for name, tc := range testCases {
+		if got := strings.Join(tc.Params(), " "); got != paramsOut {
+			t.Errorf("%v: param() returned %v, expected %v", name, got, paramsOut)
+		}
+	}
+}
+
+func TestValidateSensorMode(t *testing.T) {
+	still := NewStill()
+	still.Camera.SensorMode = 7
+	still.Width = 1920
+	still.Height = 1080
+	if err := still.Validate(); err == nil {
+		t.Errorf("Still.Validate() = nil, want an error for 1920x1080 on mode 7 (max 640x480)")
+	}
+
+	still.Width = 640
+	still.Height = 480
+	if err := still.Validate(); err != nil {
+		t.Errorf("Still.Validate() = %v, want nil", err)
+	}
+
+	vid := NewVid()
+	vid.Camera.SensorMode = 7
+	vid.Width = 640
+	vid.Height = 480
+	vid.Framerate = 10
+	if err := vid.Validate(); err == nil {
+		t.Errorf("Vid.Validate() = nil, want an error for 10fps on mode 7 (40-90fps)")
+	}
+
+	vid.Framerate = 60
+	if err := vid.Validate(); err != nil {
+		t.Errorf("Vid.Validate() = %v, want nil", err)
+	}
+}
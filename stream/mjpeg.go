@@ -0,0 +1,151 @@
+// Copyright 2013, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/dhowden/raspicam"
+)
+
+// mjpegBoundary is the multipart boundary used to separate frames in the
+// stream served by MJPEGServer.
+const mjpegBoundary = "raspicamframe"
+
+var jpegSOI = []byte{0xFF, 0xD8}
+var jpegEOI = []byte{0xFF, 0xD9}
+
+// MJPEGServer captures frames from a raspicam.CaptureCommand and serves them
+// over HTTP as a motion JPEG stream (multipart/x-mixed-replace), fanning
+// each decoded frame out to every connected client. Command should produce
+// a continuous stream of concatenated JPEG images: a Still running in
+// timelapse mode, or a Vid with Codec set to CodecMJPEG. Frames are found
+// only by their JPEG markers, so the raw YUV/RGB output of a StillYUV
+// yields no frames.
+type MJPEGServer struct {
+	Command raspicam.CaptureCommand
+
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+}
+
+// NewMJPEGServer returns an *MJPEGServer that captures frames using c.
+func NewMJPEGServer(c raspicam.CaptureCommand) *MJPEGServer {
+	return &MJPEGServer{
+		Command: c,
+		clients: make(map[chan []byte]struct{}),
+	}
+}
+
+// Run starts the underlying capture and feeds decoded frames to connected
+// clients until ctx is cancelled or the capture exits. It blocks until
+// that happens, and returns the first error encountered, if any.
+func (m *MJPEGServer) Run(ctx context.Context) error {
+	pr, pw := io.Pipe()
+	defer pr.Close()
+
+	captureErr := runCapture(ctx, m.Command, pw)
+
+	scanErr := make(chan error, 1)
+	go func() { scanErr <- m.scanFrames(pr) }()
+
+	err := <-captureErr
+	if serr := <-scanErr; serr != nil && err == nil {
+		err = serr
+	}
+	return err
+}
+
+// scanFrames reads r looking for JPEG SOI/EOI markers, broadcasting each
+// complete frame it finds to connected clients.
+func (m *MJPEGServer) scanFrames(r io.Reader) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+
+	var buf bytes.Buffer
+	inFrame := false
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		buf.WriteByte(b)
+
+		if !inFrame {
+			if bytes.HasSuffix(buf.Bytes(), jpegSOI) {
+				buf.Reset()
+				buf.Write(jpegSOI)
+				inFrame = true
+			}
+			continue
+		}
+
+		if bytes.HasSuffix(buf.Bytes(), jpegEOI) {
+			frame := make([]byte, buf.Len())
+			copy(frame, buf.Bytes())
+			m.broadcast(frame)
+			buf.Reset()
+			inFrame = false
+		}
+	}
+}
+
+// broadcast sends frame to every connected client, dropping it for clients
+// that aren't keeping up rather than blocking the capture.
+func (m *MJPEGServer) broadcast(frame []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for ch := range m.clients {
+		select {
+		case ch <- frame:
+		default:
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler, streaming frames to the client as
+// multipart/x-mixed-replace until the request is cancelled.
+func (m *MJPEGServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan []byte, 2)
+	m.mu.Lock()
+	m.clients[ch] = struct{}{}
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.clients, ch)
+		m.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", fmt.Sprintf("multipart/x-mixed-replace; boundary=%v", mjpegBoundary))
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case frame := <-ch:
+			fmt.Fprintf(w, "--%v\r\nContent-Type: image/jpeg\r\nContent-Length: %v\r\n\r\n", mjpegBoundary, len(frame))
+			if _, err := w.Write(frame); err != nil {
+				return
+			}
+			fmt.Fprint(w, "\r\n")
+			flusher.Flush()
+		}
+	}
+}
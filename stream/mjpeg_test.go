@@ -0,0 +1,87 @@
+// Copyright 2013, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stream
+
+import (
+	"bytes"
+	"testing"
+)
+
+// fakeJPEG builds a minimal SOI...EOI JPEG frame wrapping body.
+func fakeJPEG(body string) []byte {
+	b := append([]byte{}, jpegSOI...)
+	b = append(b, []byte(body)...)
+	b = append(b, jpegEOI...)
+	return b
+}
+
+func TestMJPEGServerScanFrames(t *testing.T) {
+	m := NewMJPEGServer(nil)
+
+	ch := make(chan []byte, 2)
+	m.mu.Lock()
+	m.clients[ch] = struct{}{}
+	m.mu.Unlock()
+
+	var in bytes.Buffer
+	in.WriteString("garbage before any SOI marker")
+	in.Write(fakeJPEG("frame0"))
+	in.Write(fakeJPEG("frame1"))
+
+	if err := m.scanFrames(&in); err != nil {
+		t.Fatalf("scanFrames() = %v", err)
+	}
+
+	want := [][]byte{fakeJPEG("frame0"), fakeJPEG("frame1")}
+	for i, w := range want {
+		select {
+		case got := <-ch:
+			if !bytes.Equal(got, w) {
+				t.Errorf("frame %d = %x, want %x", i, got, w)
+			}
+		default:
+			t.Fatalf("frame %d: no frame broadcast", i)
+		}
+	}
+
+	select {
+	case extra := <-ch:
+		t.Errorf("unexpected extra frame broadcast: %x", extra)
+	default:
+	}
+}
+
+func TestMJPEGServerScanFramesDropsIncompleteTrailingFrame(t *testing.T) {
+	m := NewMJPEGServer(nil)
+
+	ch := make(chan []byte, 2)
+	m.mu.Lock()
+	m.clients[ch] = struct{}{}
+	m.mu.Unlock()
+
+	var in bytes.Buffer
+	in.Write(fakeJPEG("frame0"))
+	in.Write(jpegSOI) // a second frame starts but never closes with an EOI
+	in.WriteString("truncated")
+
+	if err := m.scanFrames(&in); err != nil {
+		t.Fatalf("scanFrames() = %v", err)
+	}
+
+	select {
+	case got := <-ch:
+		if !bytes.Equal(got, fakeJPEG("frame0")) {
+			t.Errorf("frame 0 = %x, want %x", got, fakeJPEG("frame0"))
+		}
+	default:
+		t.Fatal("no frame broadcast")
+	}
+
+	select {
+	case extra := <-ch:
+		t.Errorf("unexpected frame broadcast for incomplete trailing data: %x", extra)
+	default:
+	}
+}
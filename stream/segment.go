@@ -0,0 +1,194 @@
+// Copyright 2013, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dhowden/raspicam"
+)
+
+// nalStartCode is the Annex B byte sequence marking the start of a NAL unit.
+var nalStartCode = []byte{0x00, 0x00, 0x00, 0x01}
+
+// nalTypeSPS is the H.264 NAL unit type for a Sequence Parameter Set, which
+// precedes every IDR (key) frame and is therefore a safe place to cut a
+// stream into independently decodable segments.
+const nalTypeSPS = 7
+
+// SegmentRecorder captures H.264 video from a raspicam.CaptureCommand and
+// writes it out to a sequence of files, rotating to a new file at the next
+// SPS/IDR boundary after MaxDuration or MaxBytes is exceeded. Command
+// should be a *raspicam.Vid with InlineHeaders set, so that every segment
+// starts with the SPS/PPS it needs to be decoded on its own.
+type SegmentRecorder struct {
+	Command raspicam.CaptureCommand
+
+	// Dir is the directory segment files are written to.
+	Dir string
+	// Prefix names each segment file; files are named
+	// "<Prefix><index>.h264" with a zero-padded index.
+	Prefix string
+
+	// MaxDuration rotates to a new segment once roughly this long has
+	// elapsed since the current one started. Zero disables duration-based
+	// rotation.
+	MaxDuration time.Duration
+	// MaxBytes rotates to a new segment once roughly this many bytes have
+	// been written to the current one. Zero disables size-based rotation.
+	MaxBytes int64
+}
+
+// NewSegmentRecorder returns a *SegmentRecorder that captures video using c,
+// writing segments named "<prefix><index>.h264" into dir.
+func NewSegmentRecorder(c raspicam.CaptureCommand, dir, prefix string) *SegmentRecorder {
+	return &SegmentRecorder{Command: c, Dir: dir, Prefix: prefix}
+}
+
+// Run starts the underlying capture and writes segments until ctx is
+// cancelled or the capture exits. It blocks until that happens, and
+// returns the first error encountered, if any.
+func (s *SegmentRecorder) Run(ctx context.Context) error {
+	pr, pw := io.Pipe()
+	defer pr.Close()
+
+	captureErr := runCapture(ctx, s.Command, pw)
+
+	segErr := make(chan error, 1)
+	go func() { segErr <- s.writeSegments(pr) }()
+
+	err := <-captureErr
+	if serr := <-segErr; serr != nil && err == nil {
+		err = serr
+	}
+	return err
+}
+
+func (s *SegmentRecorder) segmentPath(idx int) string {
+	return filepath.Join(s.Dir, fmt.Sprintf("%v%04d.h264", s.Prefix, idx))
+}
+
+func (s *SegmentRecorder) dueForRotation(started time.Time, written int64) bool {
+	if s.MaxDuration > 0 && time.Since(started) >= s.MaxDuration {
+		return true
+	}
+	if s.MaxBytes > 0 && written >= s.MaxBytes {
+		return true
+	}
+	return false
+}
+
+// writeSegments reads r and splits it across successive segment files,
+// cutting only at SPS NAL units once a rotation threshold has been crossed
+// so every segment is independently decodable.
+func (s *SegmentRecorder) writeSegments(r io.Reader) error {
+	idx := 0
+	f, err := os.Create(s.segmentPath(idx))
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	var written int64
+	var pending bytes.Buffer
+	// searchFrom is how far into pending we've already scanned for start
+	// codes that didn't trigger a rotation, so a single read containing
+	// several NAL units doesn't re-scan bytes we've already ruled out.
+	searchFrom := 0
+	buf := make([]byte, 64*1024)
+
+	// flushSafe writes out everything in pending except a trailing window
+	// long enough that a start code split across two reads is never missed.
+	flushSafe := func() error {
+		keep := len(nalStartCode) + 1
+		data := pending.Bytes()
+		if len(data) <= keep {
+			return nil
+		}
+		cut := len(data) - keep
+		if _, err := f.Write(data[:cut]); err != nil {
+			return err
+		}
+		written += int64(cut)
+		rest := append([]byte(nil), data[cut:]...)
+		pending.Reset()
+		pending.Write(rest)
+		if searchFrom > cut {
+			searchFrom -= cut
+		} else {
+			searchFrom = 0
+		}
+		return nil
+	}
+
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			pending.Write(buf[:n])
+
+			for {
+				data := pending.Bytes()
+				rel := bytes.Index(data[searchFrom:], nalStartCode)
+				if rel < 0 {
+					// Rescan the last few bytes next time: they may be the
+					// start of a start code completed by the next read.
+					searchFrom = len(data) - (len(nalStartCode) - 1)
+					if searchFrom < 0 {
+						searchFrom = 0
+					}
+					break
+				}
+				i := searchFrom + rel
+				if i+len(nalStartCode) >= len(data) {
+					searchFrom = i
+					break
+				}
+				nalType := data[i+len(nalStartCode)] & 0x1F
+				if i > 0 && nalType == nalTypeSPS && s.dueForRotation(started, written+int64(i)) {
+					if _, err := f.Write(data[:i]); err != nil {
+						return err
+					}
+					if err := f.Close(); err != nil {
+						return err
+					}
+					idx++
+					if f, err = os.Create(s.segmentPath(idx)); err != nil {
+						return err
+					}
+					started = time.Now()
+					written = 0
+					rest := append([]byte(nil), data[i:]...)
+					pending.Reset()
+					pending.Write(rest)
+					searchFrom = 0
+					continue
+				}
+				searchFrom = i + len(nalStartCode)
+			}
+
+			if err := flushSafe(); err != nil {
+				return err
+			}
+		}
+		if rerr != nil {
+			if _, err := f.Write(pending.Bytes()); err != nil {
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			if rerr == io.EOF {
+				return nil
+			}
+			return rerr
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2013, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stream
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// nalUnit builds an Annex B NAL unit of the given type with n bytes of
+// filler payload.
+func nalUnit(nalType byte, n int) []byte {
+	u := append([]byte{}, nalStartCode...)
+	u = append(u, nalType&0x1F)
+	for i := 0; i < n; i++ {
+		u = append(u, 0xAB)
+	}
+	return u
+}
+
+func TestSegmentRecorderWriteSegments(t *testing.T) {
+	dir := t.TempDir()
+
+	s := &SegmentRecorder{Dir: dir, Prefix: "seg", MaxBytes: 1}
+
+	var in bytes.Buffer
+	in.Write(nalUnit(nalTypeSPS, 4)) // segment 0
+	in.Write(nalUnit(1, 50))         // non-IDR slice, forces segment 0 over MaxBytes
+	in.Write(nalUnit(nalTypeSPS, 4)) // crosses the MaxBytes threshold, rotates here
+	in.Write(nalUnit(1, 10))         // segment 1
+
+	want := append([]byte(nil), in.Bytes()...)
+	if err := s.writeSegments(&in); err != nil {
+		t.Fatalf("writeSegments() = %v", err)
+	}
+
+	seg0, err := os.ReadFile(filepath.Join(dir, "seg0000.h264"))
+	if err != nil {
+		t.Fatalf("reading segment 0: %v", err)
+	}
+	seg1, err := os.ReadFile(filepath.Join(dir, "seg0001.h264"))
+	if err != nil {
+		t.Fatalf("reading segment 1: %v", err)
+	}
+
+	if !bytes.HasPrefix(seg0, nalStartCode) {
+		t.Errorf("segment 0 does not start with a NAL start code: %x", seg0)
+	}
+	if !bytes.HasPrefix(seg1, nalStartCode) {
+		t.Errorf("segment 1 does not start with a NAL start code: %x", seg1)
+	}
+	if seg1[len(nalStartCode)]&0x1F != nalTypeSPS {
+		t.Errorf("segment 1 does not start on an SPS NAL: %x", seg1)
+	}
+
+	var got bytes.Buffer
+	got.Write(seg0)
+	got.Write(seg1)
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("segments don't reconstruct the input:\ngot:  %x\nwant: %x", got.Bytes(), want)
+	}
+}
+
+func TestSegmentRecorderDueForRotation(t *testing.T) {
+	s := &SegmentRecorder{MaxDuration: time.Millisecond, MaxBytes: 100}
+
+	if s.dueForRotation(time.Now(), 0) {
+		t.Errorf("dueForRotation() = true, want false before either threshold is reached")
+	}
+	if !s.dueForRotation(time.Now(), 200) {
+		t.Errorf("dueForRotation() = false, want true once MaxBytes is exceeded")
+	}
+	time.Sleep(2 * time.Millisecond)
+	if !s.dueForRotation(time.Now().Add(-2*time.Millisecond), 0) {
+		t.Errorf("dueForRotation() = false, want true once MaxDuration is exceeded")
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright 2013, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package stream provides ready-made streaming pipelines built on top of
+// raspicam.CaptureContext: an MJPEGServer for serving frames over HTTP, and a
+// SegmentRecorder for writing a video capture out to rotating files.
+package stream
+
+import (
+	"context"
+	"io"
+
+	"github.com/dhowden/raspicam"
+)
+
+// runCapture starts c on a goroutine, writing its output into pw via
+// raspicam.CaptureContext, and returns a channel the capture's error (if
+// any) is sent on once it completes. pw is closed (with that error, so
+// EOF propagates to the reader on success) as soon as the capture exits;
+// cancelling ctx stops the underlying process rather than just the pipe,
+// so the child is never left running after Run returns.
+func runCapture(ctx context.Context, c raspicam.CaptureCommand, pw *io.PipeWriter) <-chan error {
+	errCh := make(chan error, 1)
+	go func() {
+		err := raspicam.CaptureContext(ctx, c, pw, nil)
+		pw.CloseWithError(err)
+		errCh <- err
+		close(errCh)
+	}()
+	return errCh
+}
@@ -11,6 +11,96 @@ import (
 // DefaultRaspiVidCommmand is the default command for capturing video.
 var DefaultRaspiVidCommmand = "raspivid"
 
+// FramerateRange specifies the operating range of a variable frame
+// duration capture, for requesting sub-1fps timelapse-style capture or
+// high framerates above what the integer Framerate field can express
+// cleanly. Neither raspivid nor libcamera-vid/rpicam-vid accept a
+// min-max range on --framerate (it takes a single numeric value), so Min
+// is what's actually passed on the command line; Max only feeds
+// Validate, which checks both bounds against the sensor mode's
+// documented capability.
+type FramerateRange struct {
+	Min, Max float64
+}
+
+// H264Profile is an enumeration of the supported H.264 encoder profiles.
+type H264Profile uint
+
+const (
+	H264ProfileBaseline H264Profile = iota
+	H264ProfileMain
+	H264ProfileHigh
+)
+
+var h264Profiles = [...]string{
+	"baseline",
+	"main",
+	"high",
+}
+
+// String returns the command-line parameter for the given H264Profile.
+func (p H264Profile) String() string { return h264Profiles[p] }
+
+// H264Level is an enumeration of the supported H.264 encoder levels.
+type H264Level uint
+
+const (
+	H264Level4 H264Level = iota
+	H264Level4_1
+	H264Level4_2
+)
+
+var h264Levels = [...]string{
+	"4",
+	"4.1",
+	"4.2",
+}
+
+// String returns the command-line parameter for the given H264Level.
+func (l H264Level) String() string { return h264Levels[l] }
+
+// VidCodec is an enumeration of the supported video encoder codecs.
+type VidCodec uint
+
+const (
+	CodecH264 VidCodec = iota
+	CodecMJPEG
+	CodecYUV420
+	CodecRGB
+)
+
+var vidCodecs = [...]string{
+	"h264",
+	"mjpeg",
+	"yuv420",
+	"rgb",
+}
+
+// String returns the command-line parameter for the given VidCodec.
+func (c VidCodec) String() string { return vidCodecs[c] }
+
+// IRefreshMode is an enumeration of the supported intra-refresh types,
+// used to spread key frame macroblocks out over several frames rather
+// than sending a single large I-frame.
+type IRefreshMode uint
+
+const (
+	IRefreshCyclic IRefreshMode = iota
+	IRefreshAdaptive
+	IRefreshBoth
+	IRefreshCyclicRows
+)
+
+var iRefreshModes = [...]string{
+	"cyclic",
+	"adaptive",
+	"both",
+	"cyclicrows",
+}
+
+// String returns the command-line parameter for the given IRefreshMode.
+func (r IRefreshMode) String() string { return iRefreshModes[r] }
+
 // Vid represents the the configuration used to call raspivid.
 type Vid struct {
 	Timeout       time.Duration // Delay before image is taken
@@ -19,6 +109,20 @@ type Vid struct {
 	Framerate     int           // Requested framerate (fps)
 	IntraPeriod   int           // Intra-refresh period (key frame rate)
 
+	// FramerateRange, if non-zero, is used instead of Framerate, letting
+	// fractional framerates be requested: below 1fps for timelapse-style
+	// video, or above 120fps on the sensor modes that support it. Only
+	// Min is passed to --framerate; Max is used by Validate alone.
+	FramerateRange FramerateRange
+
+	Profile       H264Profile  // H.264 encoder profile
+	Level         H264Level    // H.264 encoder level
+	InlineHeaders bool         // Insert SPS/PPS before each IDR, so decoders can join mid-stream
+	SPSTimings    bool         // Insert frame timing information into the SPS
+	Codec         VidCodec     // Encoder codec
+	Quantisation  int          // Fixed quantisation parameter; 0 uses the bitrate target instead
+	IRefresh      IRefreshMode // Intra-refresh type
+
 	// Flag to specify whether encoder works in place or creates a new buffer.
 	// Result is preview can display either the camera output or the encoder
 	// output (with compression artifacts).
@@ -30,6 +134,10 @@ type Vid struct {
 	// command is used.
 	Command string
 
+	// Backend selects which camera stack Cmd resolves Command against when
+	// Command is blank.  Defaults to BackendAuto.
+	Backend Backend
+
 	// Additional arguments.  Default is empty.
 	Args []string
 }
@@ -53,6 +161,8 @@ func (v *Vid) String() string {
 }
 
 func (v *Vid) params() []string {
+	backend := resolveBackend(v.Backend)
+
 	var out params
 	out.add("--output", "-")
 	if v.Timeout != defaultVid.Timeout {
@@ -67,23 +177,43 @@ func (v *Vid) params() []string {
 	if v.Bitrate != defaultVid.Bitrate {
 		out.addInt("--bitrate", v.Bitrate)
 	}
-	if v.Framerate != defaultVid.Framerate {
+	if v.FramerateRange != defaultVid.FramerateRange {
+		out.addFloat64("--framerate", v.FramerateRange.Min)
+	} else if v.Framerate != defaultVid.Framerate {
 		out.addInt("--framerate", v.Framerate)
 	}
 	if v.IntraPeriod != defaultVid.IntraPeriod {
 		out.addInt("--intra", v.IntraPeriod)
 	}
-	out.add(v.Camera.params()...)
+	if v.Profile != defaultVid.Profile {
+		out.add("--profile", v.Profile.String())
+	}
+	if v.Level != defaultVid.Level {
+		out.add("--level", v.Level.String())
+	}
+	if v.InlineHeaders {
+		out.add("--inline")
+	}
+	if v.SPSTimings {
+		out.add("--spstimings")
+	}
+	if v.Codec != defaultVid.Codec && backend == BackendLibcamera {
+		out.add("--codec", v.Codec.String())
+	}
+	if v.Quantisation != defaultVid.Quantisation {
+		out.addInt("--qp", v.Quantisation)
+	}
+	if v.IRefresh != defaultVid.IRefresh {
+		out.add("--irefresh", v.IRefresh.String())
+	}
+	out.add(v.Camera.params(backend)...)
 	out.add(v.Preview.params()...)
 	return out
 }
 
 // Cmd returns the raspicam command for a Vid.
 func (v *Vid) Cmd() string {
-	if v.Command != "" {
-		return v.Command
-	}
-	return DefaultRaspiVidCommmand
+	return backendCommand(v.Command, v.Backend, kindVid)
 }
 
 // Params returns the parameters to be used in the command execution.
@@ -91,6 +221,27 @@ func (v *Vid) Params() []string {
 	return append(v.params(), v.Args...)
 }
 
+// framerateRange returns the effective framerate range: FramerateRange if
+// set, or Framerate as a zero-width range otherwise.
+func (v *Vid) framerateRange() (min, max float64) {
+	if v.FramerateRange != (FramerateRange{}) {
+		return v.FramerateRange.Min, v.FramerateRange.Max
+	}
+	return float64(v.Framerate), float64(v.Framerate)
+}
+
+// Validate checks Width/Height and the effective framerate range against
+// the documented capabilities of Camera.SensorMode, if SensorMode is set
+// to anything this package knows about, returning an error describing the
+// mismatch if not.
+func (v *Vid) Validate() error {
+	if err := validateSensorResolution(v.Camera.SensorMode, v.Width, v.Height); err != nil {
+		return err
+	}
+	min, max := v.framerateRange()
+	return validateSensorFramerate(v.Camera.SensorMode, min, max)
+}
+
 // NewVid returns a new *Vid struct setup with the default configuration.
 func NewVid() *Vid {
 	newVid := defaultVid
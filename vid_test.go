@@ -0,0 +1,57 @@
+// Copyright 2013, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package raspicam
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVidEncoderParams(t *testing.T) {
+	const paramsOut = "--output - --profile main --level 4.1 --inline --spstimings --codec mjpeg --qp 20 --irefresh adaptive"
+
+	vid := NewVid()
+	vid.Backend = BackendLibcamera
+	vid.Profile = H264ProfileMain
+	vid.Level = H264Level4_1
+	vid.InlineHeaders = true
+	vid.SPSTimings = true
+	vid.Codec = CodecMJPEG
+	vid.Quantisation = 20
+	vid.IRefresh = IRefreshAdaptive
+
+	if got := strings.Join(vid.Params(), " "); got != paramsOut {
+		t.Errorf("Vid: param() returned %v, expected %v", got, paramsOut)
+	}
+}
+
+// TestVidEncoderParamsLegacyOmitsCodec checks that Codec, a libcamera/rpicam-only
+// concept, is not emitted against the legacy raspivid tool.
+func TestVidEncoderParamsLegacyOmitsCodec(t *testing.T) {
+	const paramsOut = "--output - --profile main"
+
+	vid := NewVid()
+	vid.Backend = BackendLegacy
+	vid.Profile = H264ProfileMain
+	vid.Codec = CodecMJPEG
+
+	if got := strings.Join(vid.Params(), " "); got != paramsOut {
+		t.Errorf("Vid: param() returned %v, expected %v", got, paramsOut)
+	}
+}
+
+// TestVidFramerateRangeParam checks that FramerateRange is passed to
+// --framerate as the single value raspivid/libcamera-vid expect (Min),
+// not a hyphenated range.
+func TestVidFramerateRangeParam(t *testing.T) {
+	const paramsOut = "--output - --framerate 0.5"
+
+	vid := NewVid()
+	vid.FramerateRange = FramerateRange{Min: 0.5, Max: 120}
+
+	if got := strings.Join(vid.Params(), " "); got != paramsOut {
+		t.Errorf("Vid: param() returned %v, expected %v", got, paramsOut)
+	}
+}